@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// cacheTTLEnv configures how long a cached ETA lookup stays valid, e.g. "10m".
+var cacheTTLEnv = "TRAVEL_CACHE_TTL"
+
+// defaultCacheTTL is used when cacheTTLEnv is unset or invalid.
+var defaultCacheTTL = 10 * time.Minute
+
+// cacheBucket is the size of the window departure times are rounded into, so
+// that invocations a few seconds apart still hit the same cache entry.
+var cacheBucket = 5 * time.Minute
+
+// coordPrecision is the number of decimal places coordinates are rounded to
+// before being used as a cache key (~11m of precision at the equator).
+const coordPrecision = 4
+
+// cacheFileEnv overrides where the cache state file is stored.
+var cacheFileEnv = "TRAVEL_CACHE_FILE"
+
+// cachedLeg is the persisted cache entry for one origin/destination/departure/mode lookup.
+type cachedLeg struct {
+	WithTraffic    time.Duration `json:"with_traffic"`
+	NoTraffic      time.Duration `json:"no_traffic"`
+	DistanceMeters float64       `json:"distance_meters"`
+	Expires        time.Time     `json:"expires"`
+}
+
+// etaCache is a small JSON-file-backed cache for ETA lookups, keyed on
+// rounded origin/destination coordinates and a departure time bucket, so
+// that repeated invocations from cron or statusbar widgets don't burn
+// provider quota when traffic realistically hasn't changed.
+type etaCache struct {
+	mu     sync.Mutex
+	path   string
+	ttl    time.Duration
+	bucket time.Duration
+	data   map[string]cachedLeg
+}
+
+func cacheFilePath() string {
+	if path := os.Getenv(cacheFileEnv); path != "" {
+		return path
+	}
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".cache")
+		}
+	}
+	return filepath.Join(dir, "traveltime", "cache.json")
+}
+
+func loadETACache() *etaCache {
+	ttl := defaultCacheTTL
+	if raw := os.Getenv(cacheTTLEnv); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			ttl = parsed
+		}
+	}
+	c := &etaCache{path: cacheFilePath(), ttl: ttl, bucket: cacheBucket, data: map[string]cachedLeg{}}
+	if b, err := os.ReadFile(c.path); err == nil {
+		_ = json.Unmarshal(b, &c.data)
+	}
+	return c
+}
+
+func (c *etaCache) key(origin, destination LatLngName, depart time.Time, mode string) string {
+	bucketed := depart.Truncate(c.bucket).Unix()
+	return fmt.Sprintf("%.*f,%.*f->%.*f,%.*f@%d:%s",
+		coordPrecision, origin.Lat, coordPrecision, origin.Lng,
+		coordPrecision, destination.Lat, coordPrecision, destination.Lng,
+		bucketed, mode)
+}
+
+func (c *etaCache) get(origin, destination LatLngName, depart time.Time, mode string) (cachedLeg, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	leg, ok := c.data[c.key(origin, destination, depart, mode)]
+	if !ok || time.Now().After(leg.Expires) {
+		return cachedLeg{}, false
+	}
+	return leg, true
+}
+
+func (c *etaCache) put(origin, destination LatLngName, depart time.Time, mode string, withTraffic, noTraffic time.Duration, distanceMeters float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[c.key(origin, destination, depart, mode)] = cachedLeg{
+		WithTraffic:    withTraffic,
+		NoTraffic:      noTraffic,
+		DistanceMeters: distanceMeters,
+		Expires:        time.Now().Add(c.ttl),
+	}
+	c.save()
+}
+
+func (c *etaCache) save() {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return
+	}
+	b, err := json.Marshal(c.data)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(c.path, b, 0o644)
+}
+
+// cachingProvider wraps another ETAProvider with the TTL cache, so cache hits
+// never touch the network or a provider's quota.
+type cachingProvider struct {
+	next  ETAProvider
+	cache *etaCache
+}
+
+func newCachingProvider(next ETAProvider, cache *etaCache) *cachingProvider {
+	return &cachingProvider{next: next, cache: cache}
+}
+
+func (c *cachingProvider) Name() string { return c.next.Name() }
+
+func (c *cachingProvider) TravelTime(ctx context.Context, origin, destination LatLngName, depart time.Time, mode string) (withTraffic, noTraffic time.Duration, distanceMeters float64, err error) {
+	if leg, ok := c.cache.get(origin, destination, depart, mode); ok {
+		return leg.WithTraffic, leg.NoTraffic, leg.DistanceMeters, nil
+	}
+	withTraffic, noTraffic, distanceMeters, err = c.next.TravelTime(ctx, origin, destination, depart, mode)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	c.cache.put(origin, destination, depart, mode, withTraffic, noTraffic, distanceMeters)
+	return withTraffic, noTraffic, distanceMeters, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestETACache(t *testing.T, ttl time.Duration) *etaCache {
+	t.Helper()
+	return &etaCache{path: t.TempDir() + "/cache.json", ttl: ttl, bucket: cacheBucket, data: map[string]cachedLeg{}}
+}
+
+func TestETACacheHitWithinTTL(t *testing.T) {
+	c := newTestETACache(t, time.Hour)
+	origin := LatLngName{Name: "home"}
+	destination := LatLngName{Name: "work"}
+	depart := time.Now()
+
+	c.put(origin, destination, depart, "driving", 20*time.Minute, 15*time.Minute, 12000)
+
+	leg, ok := c.get(origin, destination, depart, "driving")
+	if !ok {
+		t.Fatal("get() ok = false, want true for a freshly put entry")
+	}
+	if leg.WithTraffic != 20*time.Minute || leg.NoTraffic != 15*time.Minute || leg.DistanceMeters != 12000 {
+		t.Fatalf("get() = %+v, want WithTraffic=20m NoTraffic=15m DistanceMeters=12000", leg)
+	}
+}
+
+func TestETACacheExpiresAfterTTL(t *testing.T) {
+	c := newTestETACache(t, -time.Minute)
+	origin := LatLngName{Name: "home"}
+	destination := LatLngName{Name: "work"}
+	depart := time.Now()
+
+	c.put(origin, destination, depart, "driving", 20*time.Minute, 15*time.Minute, 12000)
+
+	if _, ok := c.get(origin, destination, depart, "driving"); ok {
+		t.Fatal("get() ok = true, want false once the entry's TTL has elapsed")
+	}
+}
+
+func TestETACacheMissOnDifferentMode(t *testing.T) {
+	c := newTestETACache(t, time.Hour)
+	origin := LatLngName{Name: "home"}
+	destination := LatLngName{Name: "work"}
+	depart := time.Now()
+
+	c.put(origin, destination, depart, "driving", 20*time.Minute, 15*time.Minute, 12000)
+
+	if _, ok := c.get(origin, destination, depart, "walking"); ok {
+		t.Fatal("get() ok = true for a different mode, want false")
+	}
+}
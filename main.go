@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
@@ -16,6 +18,10 @@ import (
 )
 
 var (
+	// apiEnv is always required, even when TRAVEL_PROVIDERS omits "google":
+	// the Google Maps client it configures is also used for geolocating the
+	// current position (see fetchTravelResult), which has no non-Google
+	// fallback yet.
 	apiEnv          = "GOOGLE_API_KEY"
 	workEnv         = "TRAVEL_WORK_COORD"
 	homeEnv         = "TRAVEL_HOME_COORD"
@@ -23,6 +29,14 @@ var (
 	defaultFormat   = `{{ .Origin.Name }}: {{ .WithTraffic }} {{ .Deviation.Absolute }}min`
 )
 
+// modesEnv lists the travel modes to request in parallel, e.g.
+// "driving,transit,bicycling,walking". Results land in TravelResult.Alternatives.
+var modesEnv = "TRAVEL_MODES"
+
+// defaultModes is used when modesEnv is unset, preserving the original
+// driving-only behavior.
+var defaultModes = []string{string(maps.TravelModeDriving)}
+
 func main() {
 	apiKey := os.Getenv(apiEnv)
 	if apiKey == "" {
@@ -45,77 +59,143 @@ func main() {
 	if err != nil {
 		log.Fatalf("invalid format %q: %e", defaultFormat, err)
 	}
-	work, err := parseLatLngName(workArg)
+	work, err := parseLatLngNames(workArg)
 	if err != nil {
 		log.Fatal(err)
 	}
-	home, err := parseLatLngName(homeArg)
+	home, err := parseLatLngNames(homeArg)
 	if err != nil {
 		log.Fatal(err)
 	}
+	modes := defaultModes
+	if raw := os.Getenv(modesEnv); raw != "" {
+		modes = nil
+		for _, mode := range strings.Split(raw, ",") {
+			modes = append(modes, strings.TrimSpace(mode))
+		}
+	}
 
-	client, err := maps.NewClient(maps.WithAPIKey(apiKey))
+	httpClient := &http.Client{Transport: newRetryTransport()}
+	client, err := maps.NewClient(maps.WithAPIKey(apiKey), maps.WithHTTPClient(httpClient))
 	if err != nil {
 		log.Fatal(err)
 	}
+	// Look up the optimal and actual travel duration, falling back through
+	// TRAVEL_PROVIDERS in order if one fails or has exhausted its quota.
+	chain := buildProviderChain(client, func(msg string) { log.Println(msg) })
+	provider := newCachingProvider(chain, loadETACache())
+
+	if addr := os.Getenv(serveAddrEnv); addr != "" {
+		serve(addr, client, provider, work, home, modes)
+		return
+	}
 
-	// Traveltime needs your current position to calculate which of the given locations is the origin.
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
+	result, err := fetchTravelResult(ctx, client, provider, work, home, modes)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := outTemplate.Execute(os.Stdout, result); err != nil {
+		log.Fatal("failed to execute template: ", err)
+	}
+}
+
+// fetchTravelResult resolves the current position, picks the nearest
+// candidate on each side as origin/destination, and looks up the travel time
+// for every mode in parallel through provider. It is shared by the one-shot
+// CLI path and the daemon's poll loop.
+func fetchTravelResult(ctx context.Context, client *maps.Client, provider ETAProvider, work, home []LatLngName, modes []string) (*TravelResult, error) {
+	// Traveltime needs your current position to calculate which of the given
+	// locations is the origin. This always goes through Google's Geolocation
+	// API, regardless of TRAVEL_PROVIDERS, so a Google API key is required
+	// even if every ETA lookup below is served by another provider.
 	locationResult, err := client.Geolocate(ctx, &maps.GeolocationRequest{ConsiderIP: true})
 	if err != nil {
-		log.Fatal("failed to fetch geolocation: ", err)
+		return nil, fmt.Errorf("failed to fetch geolocation: %w", err)
 	}
-	// Use the current position to calculate the origin.
+	// Use the current position to calculate the origin, out of every candidate on each side.
 	origin, destination := findDirection(work, home, locationResult.Location)
-	// Call an upstream API for the optimal and actual travel duration.
-	// For now use Google's Distance Matrix API.
-	distanceResult, err := client.DistanceMatrix(ctx, &maps.DistanceMatrixRequest{
-		Origins:       []string{origin.LatLng.String()},
-		Destinations:  []string{destination.LatLng.String()},
-		Mode:          maps.TravelModeDriving,
-		DepartureTime: fmt.Sprintf("%d", time.Now().Unix()),
-		TrafficModel:  maps.TrafficModelBestGuess,
-	})
+
+	depart := time.Now()
+	legs, primary, err := fetchLegs(ctx, provider, origin, destination, depart, modes)
 	if err != nil {
-		log.Fatal("failed to fetch distance matrix: ", err)
+		return nil, err
 	}
 	// Traveltime is designed to return the optimal travel duration, the time you would travel without traffic.
 	// It also returns the actual travel duration, the time you should plan considering the current traffic situation.
-	//
-	// Calculate those information from the API response.
-	result := &TravelResult{
-		Origin:      origin,
-		Destination: destination,
-		WithTraffic: int(getWithTrafficDuration(distanceResult).Minutes()),
-		NoTraffic:   int(getNoTrafficeDuration(distanceResult).Minutes()),
-		Deviation:   newDeviation(distanceResult),
-	}
-
-	if err := outTemplate.Execute(os.Stdout, result); err != nil {
-		log.Fatal("failed to execute template: ", err)
-	}
+	// WithTraffic/NoTraffic/Deviation mirror the driving mode (or the first
+	// successful mode, if driving wasn't requested or failed) for backward compatibility
+	// with the default output template.
+	return &TravelResult{
+		Origin:       origin,
+		Destination:  destination,
+		WithTraffic:  int(primary.withTraffic.Minutes()),
+		NoTraffic:    int(primary.noTraffic.Minutes()),
+		Deviation:    newDeviationFromDurations(primary.withTraffic, primary.noTraffic),
+		Alternatives: legs,
+	}, nil
 }
 
-func getWithTrafficDuration(distanceResult *maps.DistanceMatrixResponse) time.Duration {
-	return distanceResult.Rows[0].Elements[0].DurationInTraffic
+// modeResult is the raw per-mode ETAProvider lookup backing a TravelLeg.
+type modeResult struct {
+	mode                   string
+	withTraffic, noTraffic time.Duration
+	err                    error
 }
 
-func getNoTrafficeDuration(distanceResult *maps.DistanceMatrixResponse) time.Duration {
-	return distanceResult.Rows[0].Elements[0].Duration
+// fetchLegs requests every mode from provider in parallel, returning one
+// TravelLeg per mode that succeeded, plus the modeResult used for the legacy
+// single-mode TravelResult fields (driving if present, else the first
+// success).
+func fetchLegs(ctx context.Context, provider ETAProvider, origin, destination LatLngName, depart time.Time, modes []string) (legs []TravelLeg, primary modeResult, err error) {
+	results := make([]modeResult, len(modes))
+	legsByIndex := make([]*TravelLeg, len(modes))
+	var wg sync.WaitGroup
+	for i, mode := range modes {
+		wg.Add(1)
+		go func(i int, mode string) {
+			defer wg.Done()
+			withTraffic, noTraffic, distanceMeters, err := provider.TravelTime(ctx, origin, destination, depart, mode)
+			results[i] = modeResult{mode: mode, withTraffic: withTraffic, noTraffic: noTraffic, err: err}
+			if err == nil {
+				legsByIndex[i] = &TravelLeg{
+					Mode:       mode,
+					Duration:   int(withTraffic.Minutes()),
+					DistanceKm: distanceMeters / 1000,
+					Depart:     depart,
+					Arrive:     depart.Add(withTraffic),
+				}
+			}
+		}(i, mode)
+	}
+	wg.Wait()
+
+	var errs []string
+	for i, leg := range legsByIndex {
+		if leg != nil {
+			legs = append(legs, *leg)
+			if primary.mode == "" || results[i].mode == string(maps.TravelModeDriving) {
+				primary = results[i]
+			}
+			continue
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", results[i].mode, results[i].err))
+	}
+	if len(legs) == 0 {
+		return nil, modeResult{}, fmt.Errorf("failed to fetch travel time for any mode: %s", strings.Join(errs, "; "))
+	}
+	return legs, primary, nil
 }
 
-func getAbsoluteDeviation(distanceResult *maps.DistanceMatrixResponse) float64 {
-	withTraffic := distanceResult.Rows[0].Elements[0].DurationInTraffic.Minutes()
-	noTraffic := distanceResult.Rows[0].Elements[0].Duration.Minutes()
-	return (withTraffic - noTraffic)
+func getAbsoluteDeviation(withTraffic, noTraffic time.Duration) float64 {
+	return withTraffic.Minutes() - noTraffic.Minutes()
 }
 
-func getRelativeDeviation(distanceResult *maps.DistanceMatrixResponse) float64 {
-	withTraffic := distanceResult.Rows[0].Elements[0].DurationInTraffic.Seconds()
-	noTraffic := distanceResult.Rows[0].Elements[0].Duration.Seconds()
-	return ((100 / noTraffic * withTraffic) - 100)
+func getRelativeDeviation(withTraffic, noTraffic time.Duration) float64 {
+	return (100 / noTraffic.Seconds() * withTraffic.Seconds()) - 100
 }
 
 // TravelResult holds all informations about a travel.
@@ -129,6 +209,38 @@ type TravelResult struct {
 	NoTraffic int
 	// Deviation contains the difference between NoTraffic and WithTraffic in different formats.
 	Deviation Deviation
+	// Alternatives holds one TravelLeg per mode requested via TRAVEL_MODES
+	// whose lookup succeeded; failed modes are left out.
+	Alternatives []TravelLeg
+}
+
+// Fastest returns the quickest of TravelResult.Alternatives, for use in
+// output templates as `{{ .Fastest.Mode }}`. It returns the zero TravelLeg
+// if Alternatives is empty.
+func (r *TravelResult) Fastest() TravelLeg {
+	if len(r.Alternatives) == 0 {
+		return TravelLeg{}
+	}
+	fastest := r.Alternatives[0]
+	for _, leg := range r.Alternatives[1:] {
+		if leg.Duration < fastest.Duration {
+			fastest = leg
+		}
+	}
+	return fastest
+}
+
+// TravelLeg is the travel time, distance, and timing for one mode of the
+// journey from TravelResult.Origin to TravelResult.Destination.
+type TravelLeg struct {
+	// Mode is the maps.Mode this leg was requested with, e.g. "driving".
+	Mode string
+	// Duration is the travel time for this mode, in minutes.
+	Duration int
+	// DistanceKm is the route distance for this mode, in kilometers.
+	DistanceKm float64
+	// Depart and Arrive are the assumed departure time and the resulting arrival time.
+	Depart, Arrive time.Time
 }
 
 // Deviation contains different versions of the delay induced by traffic on the travel.
@@ -139,27 +251,56 @@ type Deviation struct {
 	Absolute string
 }
 
-func newDeviation(distanceResult *maps.DistanceMatrixResponse) Deviation {
+func newDeviationFromDurations(withTraffic, noTraffic time.Duration) Deviation {
 	return Deviation{
-		Relative: fmt.Sprintf("%+d", int(getRelativeDeviation(distanceResult))),
-		Absolute: fmt.Sprintf("%+d", int(getAbsoluteDeviation(distanceResult))),
+		Relative: fmt.Sprintf("%+d", int(getRelativeDeviation(withTraffic, noTraffic))),
+		Absolute: fmt.Sprintf("%+d", int(getAbsoluteDeviation(withTraffic, noTraffic))),
 	}
 }
 
-// findDirection calculates which coordinate is less far away from your current location.
-// Based on this information in which direction you need to travel.
-// Your origin is the nearest point to your current location.
-func findDirection(pointA, pointB LatLngName, location maps.LatLng) (origin, destination LatLngName) {
-	distance1 := calculateDistance(pointA.LatLng, location)
-	distance2 := calculateDistance(pointB.LatLng, location)
-	if distance1 < distance2 {
-		return pointA, pointB
+// findDirection picks the nearest candidate in each group to your current
+// location, then returns whichever group's nearest candidate is closer as
+// the origin. Based on this information in which direction you need to travel.
+func findDirection(groupA, groupB []LatLngName, location maps.LatLng) (origin, destination LatLngName) {
+	nearestA, distanceA := nearestCandidate(groupA, location)
+	nearestB, distanceB := nearestCandidate(groupB, location)
+	if distanceA < distanceB {
+		return nearestA, nearestB
 	}
-	return pointB, pointA
+	return nearestB, nearestA
 }
 
+// nearestCandidate returns whichever candidate is closest to location, and
+// its distance in kilometers.
+func nearestCandidate(candidates []LatLngName, location maps.LatLng) (nearest LatLngName, distanceKm float64) {
+	nearest = candidates[0]
+	distanceKm = calculateDistance(nearest.LatLng, location)
+	for _, candidate := range candidates[1:] {
+		if d := calculateDistance(candidate.LatLng, location); d < distanceKm {
+			nearest, distanceKm = candidate, d
+		}
+	}
+	return nearest, distanceKm
+}
+
+// earthRadiusKm is the mean radius of the Earth, used by calculateDistance.
+const earthRadiusKm = 6371
+
+// calculateDistance returns the great-circle distance between two
+// coordinates in kilometers, using the haversine formula. A simple Euclidean
+// distance on raw lat/lng picks the wrong nearest point near the poles or
+// when the two candidates straddle very different longitudes, since a degree
+// of longitude shrinks with latitude.
 func calculateDistance(point1, point2 maps.LatLng) float64 {
-	return math.Sqrt(math.Pow(point2.Lat-point1.Lat, 2) + math.Pow(point2.Lng-point1.Lng, 2))
+	lat1 := point1.Lat * math.Pi / 180
+	lat2 := point2.Lat * math.Pi / 180
+	deltaLat := (point2.Lat - point1.Lat) * math.Pi / 180
+	deltaLng := (point2.Lng - point1.Lng) * math.Pi / 180
+
+	a := math.Sin(deltaLat/2)*math.Sin(deltaLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(deltaLng/2)*math.Sin(deltaLng/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return earthRadiusKm * c
 }
 
 // LatLngName extends the googlemaps.github.io/maps.LatLng struct with a name.
@@ -188,3 +329,26 @@ func parseLatLngName(location string) (LatLngName, error) {
 	result.Name = name
 	return result, nil
 }
+
+// parseLatLngNames parses one or more ';'-separated "name,lat,lng" entries,
+// so TRAVEL_WORK_COORD/TRAVEL_HOME_COORD can each list several candidate
+// locations (e.g. home, parents' house, gym) and findDirection picks the
+// nearest one.
+func parseLatLngNames(locations string) ([]LatLngName, error) {
+	var result []LatLngName
+	for _, entry := range strings.Split(locations, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parsed, err := parseLatLngName(entry)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, parsed)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no coordinates provided")
+	}
+	return result, nil
+}
@@ -3,8 +3,11 @@ package main
 import (
 	"bytes"
 	"log"
+	"math"
 	"testing"
 	"text/template"
+
+	"googlemaps.github.io/maps"
 )
 
 func TestTemplateDefaultFormat(t *testing.T) {
@@ -32,3 +35,78 @@ func TestTemplateDefaultFormat(t *testing.T) {
 		t.Fatalf("template returned unexpected format. got=%q want=%q", buf.String(), expected)
 	}
 }
+
+func TestCalculateDistanceKnownCityPairs(t *testing.T) {
+	tests := []struct {
+		name           string
+		point1, point2 maps.LatLng
+		wantKm         float64
+		tolerance      float64
+	}{
+		{
+			name:      "Berlin to Munich",
+			point1:    maps.LatLng{Lat: 52.5200, Lng: 13.4050},
+			point2:    maps.LatLng{Lat: 48.1351, Lng: 11.5820},
+			wantKm:    504,
+			tolerance: 5,
+		},
+		{
+			name:      "London to Paris",
+			point1:    maps.LatLng{Lat: 51.5074, Lng: -0.1278},
+			point2:    maps.LatLng{Lat: 48.8566, Lng: 2.3522},
+			wantKm:    344,
+			tolerance: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculateDistance(tt.point1, tt.point2)
+			if math.Abs(got-tt.wantKm) > tt.tolerance {
+				t.Fatalf("calculateDistance() = %.1fkm, want %.1fkm +/- %.1fkm", got, tt.wantKm, tt.tolerance)
+			}
+		})
+	}
+}
+
+// TestFindDirectionHaversineDisagreesWithEuclidean covers a case near the
+// pole where a degree of longitude is much shorter than a degree of
+// latitude, so naive Euclidean lat/lng distance picks the wrong origin.
+func TestFindDirectionHaversineDisagreesWithEuclidean(t *testing.T) {
+	pointA := LatLngName{Name: "A", LatLng: maps.LatLng{Lat: 89, Lng: 10}}
+	pointB := LatLngName{Name: "B", LatLng: maps.LatLng{Lat: 80, Lng: 1}}
+	location := maps.LatLng{Lat: 89, Lng: 0}
+
+	euclideanA := math.Sqrt(math.Pow(pointA.Lat-location.Lat, 2) + math.Pow(pointA.Lng-location.Lng, 2))
+	euclideanB := math.Sqrt(math.Pow(pointB.Lat-location.Lat, 2) + math.Pow(pointB.Lng-location.Lng, 2))
+	if euclideanA < euclideanB {
+		t.Fatalf("test setup invalid: expected naive Euclidean distance to prefer pointB")
+	}
+
+	origin, _ := findDirection([]LatLngName{pointA}, []LatLngName{pointB}, location)
+	if origin.Name != "A" {
+		t.Fatalf("findDirection() origin = %q, want %q (haversine should find A nearer near the pole)", origin.Name, "A")
+	}
+}
+
+// TestFindDirectionPicksNearestCandidateInGroup covers TRAVEL_HOME_COORD/
+// TRAVEL_WORK_COORD listing several candidates per side (e.g. home, parents'
+// house, gym): findDirection should pick the nearest candidate within each
+// group before comparing the two groups.
+func TestFindDirectionPicksNearestCandidateInGroup(t *testing.T) {
+	home := []LatLngName{
+		{Name: "home", LatLng: maps.LatLng{Lat: 52.0, Lng: 13.0}},
+		{Name: "parents", LatLng: maps.LatLng{Lat: 50.0, Lng: 8.0}},
+	}
+	work := []LatLngName{
+		{Name: "office", LatLng: maps.LatLng{Lat: 52.52, Lng: 13.405}},
+	}
+	location := maps.LatLng{Lat: 52.01, Lng: 13.01}
+
+	origin, destination := findDirection(work, home, location)
+	if origin.Name != "home" {
+		t.Fatalf("findDirection() origin = %q, want %q (nearest candidate in the home group)", origin.Name, "home")
+	}
+	if destination.Name != "office" {
+		t.Fatalf("findDirection() destination = %q, want %q", destination.Name, "office")
+	}
+}
@@ -0,0 +1,537 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+// providersEnv lists the ordered fallback chain of ETA providers to try,
+// e.g. "google,osrm,mapbox". Unknown or unconfigured providers are skipped.
+var providersEnv = "TRAVEL_PROVIDERS"
+
+// defaultProviders is used when providersEnv is unset, preserving the
+// original Google-only behavior.
+var defaultProviders = []string{"google"}
+
+// ETAProvider looks up the travel time between two points, for a given mode
+// and departure time. Implementations should return an error that satisfies
+// isQuotaError when the provider's free tier or rate limit is exhausted, so
+// the chain can remember to skip it for quotaBackoff, and an error for
+// a mode they don't support so the chain can fall back.
+type ETAProvider interface {
+	// Name identifies the provider in TRAVEL_PROVIDERS and in quota bookkeeping.
+	Name() string
+	// TravelTime returns the traffic-aware and traffic-free durations and the
+	// distance in meters for the leg.
+	TravelTime(ctx context.Context, origin, destination LatLngName, depart time.Time, mode string) (withTraffic, noTraffic time.Duration, distanceMeters float64, err error)
+}
+
+// providerChain tries each ETAProvider in order, falling back to the next one
+// on error or once a provider's quota is known to be exhausted.
+type providerChain struct {
+	providers []ETAProvider
+	quota     *quotaTracker
+}
+
+func newProviderChain(providers []ETAProvider, quota *quotaTracker) *providerChain {
+	return &providerChain{providers: providers, quota: quota}
+}
+
+// Name identifies the chain by its first provider. It does not report which
+// provider actually served a given request.
+func (c *providerChain) Name() string {
+	if len(c.providers) == 0 {
+		return "none"
+	}
+	return c.providers[0].Name()
+}
+
+// TravelTime implements ETAProvider by delegating to the first provider in
+// the chain that is neither exhausted nor failing.
+func (c *providerChain) TravelTime(ctx context.Context, origin, destination LatLngName, depart time.Time, mode string) (withTraffic, noTraffic time.Duration, distanceMeters float64, err error) {
+	var errs []string
+	for _, p := range c.providers {
+		if c.quota.exhausted(p.Name()) {
+			errs = append(errs, fmt.Sprintf("%s: quota exhausted", p.Name()))
+			continue
+		}
+		withTraffic, noTraffic, distanceMeters, err = p.TravelTime(ctx, origin, destination, depart, mode)
+		if err != nil {
+			if isQuotaError(err) {
+				c.quota.markExhausted(p.Name())
+			}
+			errs = append(errs, fmt.Sprintf("%s: %s", p.Name(), err))
+			continue
+		}
+		return withTraffic, noTraffic, distanceMeters, nil
+	}
+	return 0, 0, 0, fmt.Errorf("all providers failed: %s", strings.Join(errs, "; "))
+}
+
+// isQuotaError reports whether err looks like a rate-limit or quota-exhaustion
+// response, across the different vocabularies each provider's API uses.
+func isQuotaError(err error) bool {
+	msg := err.Error()
+	for _, marker := range []string{"OVER_QUERY_LIMIT", "429", "rate limit", "quota"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// newProvider constructs the ETAProvider registered under name, or an error
+// if name is unknown or missing required configuration.
+func newProvider(name string, client *maps.Client) (ETAProvider, error) {
+	switch name {
+	case "google":
+		return &googleProvider{client: client}, nil
+	case "osrm":
+		return newOSRMProvider(), nil
+	case "mapbox":
+		return newMapboxProvider()
+	case "here":
+		return newHEREProvider()
+	case "openrouteservice", "ors":
+		return newORSProvider()
+	default:
+		return nil, fmt.Errorf("unknown ETA provider %q", name)
+	}
+}
+
+// buildProviderChain parses providersEnv into an ordered list of providers,
+// skipping any that fail to configure (e.g. missing API key) with a warning
+// rather than aborting the whole chain.
+func buildProviderChain(client *maps.Client, warn func(string)) *providerChain {
+	names := defaultProviders
+	if raw := os.Getenv(providersEnv); raw != "" {
+		names = strings.Split(raw, ",")
+	}
+	var providers []ETAProvider
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		p, err := newProvider(name, client)
+		if err != nil {
+			warn(fmt.Sprintf("skipping ETA provider %q: %s", name, err))
+			continue
+		}
+		providers = append(providers, p)
+	}
+	return newProviderChain(providers, loadQuotaTracker())
+}
+
+// googleProvider uses the Google Distance Matrix API, preserving the
+// original single-provider behavior of traveltime.
+type googleProvider struct {
+	client *maps.Client
+}
+
+func (p *googleProvider) Name() string { return "google" }
+
+func (p *googleProvider) TravelTime(ctx context.Context, origin, destination LatLngName, depart time.Time, mode string) (withTraffic, noTraffic time.Duration, distanceMeters float64, err error) {
+	req := &maps.DistanceMatrixRequest{
+		Origins:      []string{origin.LatLng.String()},
+		Destinations: []string{destination.LatLng.String()},
+		Mode:         maps.Mode(mode),
+	}
+	if mode == string(maps.TravelModeDriving) {
+		// Traffic-aware duration only exists for driving, and only when a
+		// departure time and traffic model are supplied.
+		req.DepartureTime = fmt.Sprintf("%d", depart.Unix())
+		req.TrafficModel = maps.TrafficModelBestGuess
+	}
+	result, err := p.client.DistanceMatrix(ctx, req)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	element := result.Rows[0].Elements[0]
+	noTraffic = element.Duration
+	withTraffic = element.Duration
+	if element.DurationInTraffic > 0 {
+		withTraffic = element.DurationInTraffic
+	}
+	return withTraffic, noTraffic, float64(element.Distance.Meters), nil
+}
+
+// osrmProvider queries a self-hosted or public OSRM/Valhalla-compatible route
+// server. OSRM has no notion of live traffic, so withTraffic and noTraffic
+// are reported as equal.
+type osrmProvider struct {
+	baseURL string
+	http    *http.Client
+}
+
+// osrmURLEnv overrides the OSRM server to query; it defaults to the public
+// demo server, which is rate-limited and unsuitable for production use.
+var osrmURLEnv = "TRAVEL_OSRM_URL"
+
+func newOSRMProvider() *osrmProvider {
+	baseURL := os.Getenv(osrmURLEnv)
+	if baseURL == "" {
+		baseURL = "https://router.project-osrm.org"
+	}
+	return &osrmProvider{baseURL: strings.TrimRight(baseURL, "/"), http: http.DefaultClient}
+}
+
+func (p *osrmProvider) Name() string { return "osrm" }
+
+// osrmProfiles maps a maps.Mode to the OSRM routing profile name. OSRM
+// has no transit profile.
+var osrmProfiles = map[string]string{
+	string(maps.TravelModeDriving):   "driving",
+	string(maps.TravelModeWalking):   "foot",
+	string(maps.TravelModeBicycling): "bike",
+}
+
+func (p *osrmProvider) TravelTime(ctx context.Context, origin, destination LatLngName, depart time.Time, mode string) (withTraffic, noTraffic time.Duration, distanceMeters float64, err error) {
+	profile, ok := osrmProfiles[mode]
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("osrm: unsupported mode %q", mode)
+	}
+	url := fmt.Sprintf("%s/route/v1/%s/%f,%f;%f,%f?overview=false",
+		p.baseURL, profile, origin.Lng, origin.Lat, destination.Lng, destination.Lat)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, 0, fmt.Errorf("osrm: unexpected status %d", resp.StatusCode)
+	}
+	var out struct {
+		Routes []struct {
+			Duration float64 `json:"duration"`
+			Distance float64 `json:"distance"`
+		} `json:"routes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, 0, 0, err
+	}
+	if len(out.Routes) == 0 {
+		return 0, 0, 0, fmt.Errorf("osrm: no route found")
+	}
+	d := time.Duration(out.Routes[0].Duration) * time.Second
+	return d, d, out.Routes[0].Distance, nil
+}
+
+// mapboxProvider queries the Mapbox Directions API.
+type mapboxProvider struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+var mapboxTokenEnv = "TRAVEL_MAPBOX_TOKEN"
+
+func newMapboxProvider() (*mapboxProvider, error) {
+	token := os.Getenv(mapboxTokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("missing %s", mapboxTokenEnv)
+	}
+	return &mapboxProvider{baseURL: "https://api.mapbox.com", token: token, http: http.DefaultClient}, nil
+}
+
+func (p *mapboxProvider) Name() string { return "mapbox" }
+
+// mapboxProfiles maps a maps.Mode to a Mapbox Directions profile.
+// Mapbox has no transit profile.
+var mapboxProfiles = map[string]string{
+	string(maps.TravelModeDriving):   "driving-traffic",
+	string(maps.TravelModeWalking):   "walking",
+	string(maps.TravelModeBicycling): "cycling",
+}
+
+func (p *mapboxProvider) TravelTime(ctx context.Context, origin, destination LatLngName, depart time.Time, mode string) (withTraffic, noTraffic time.Duration, distanceMeters float64, err error) {
+	profile, ok := mapboxProfiles[mode]
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("mapbox: unsupported mode %q", mode)
+	}
+	url := fmt.Sprintf("%s/directions/v5/mapbox/%s/%f,%f;%f,%f?access_token=%s",
+		p.baseURL, profile, origin.Lng, origin.Lat, destination.Lng, destination.Lat, p.token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return 0, 0, 0, fmt.Errorf("mapbox: quota exhausted (429)")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, 0, fmt.Errorf("mapbox: unexpected status %d", resp.StatusCode)
+	}
+	var out struct {
+		Routes []struct {
+			Duration        float64 `json:"duration"`
+			DurationTraffic float64 `json:"duration_typical"`
+			Distance        float64 `json:"distance"`
+		} `json:"routes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, 0, 0, err
+	}
+	if len(out.Routes) == 0 {
+		return 0, 0, 0, fmt.Errorf("mapbox: no route found")
+	}
+	route := out.Routes[0]
+	withTraffic = time.Duration(route.Duration) * time.Second
+	noTraffic = withTraffic
+	if route.DurationTraffic > 0 {
+		noTraffic = time.Duration(route.DurationTraffic) * time.Second
+	}
+	return withTraffic, noTraffic, route.Distance, nil
+}
+
+// hereProvider queries the HERE Routing API (v8).
+type hereProvider struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+var hereAPIKeyEnv = "TRAVEL_HERE_API_KEY"
+
+func newHEREProvider() (*hereProvider, error) {
+	apiKey := os.Getenv(hereAPIKeyEnv)
+	if apiKey == "" {
+		return nil, fmt.Errorf("missing %s", hereAPIKeyEnv)
+	}
+	return &hereProvider{baseURL: "https://router.hereapi.com", apiKey: apiKey, http: http.DefaultClient}, nil
+}
+
+func (p *hereProvider) Name() string { return "here" }
+
+// hereTransportModes maps a maps.Mode to a HERE Routing API v8
+// transportMode. HERE's transit routing is a separate API and isn't
+// supported here.
+var hereTransportModes = map[string]string{
+	string(maps.TravelModeDriving):   "car",
+	string(maps.TravelModeWalking):   "pedestrian",
+	string(maps.TravelModeBicycling): "bicycle",
+}
+
+func (p *hereProvider) TravelTime(ctx context.Context, origin, destination LatLngName, depart time.Time, mode string) (withTraffic, noTraffic time.Duration, distanceMeters float64, err error) {
+	transportMode, ok := hereTransportModes[mode]
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("here: unsupported mode %q", mode)
+	}
+	query := url.Values{
+		"transportMode": {transportMode},
+		"origin":        {fmt.Sprintf("%f,%f", origin.Lat, origin.Lng)},
+		"destination":   {fmt.Sprintf("%f,%f", destination.Lat, destination.Lng)},
+		"return":        {"summary"},
+		"departureTime": {depart.Format(time.RFC3339)},
+		"apiKey":        {p.apiKey},
+	}
+	reqURL := p.baseURL + "/v8/routes?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return 0, 0, 0, fmt.Errorf("here: quota exhausted (429)")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, 0, fmt.Errorf("here: unexpected status %d", resp.StatusCode)
+	}
+	var out struct {
+		Routes []struct {
+			Sections []struct {
+				Summary struct {
+					Duration     int `json:"duration"`
+					BaseDuration int `json:"baseDuration"`
+					Length       int `json:"length"`
+				} `json:"summary"`
+			} `json:"sections"`
+		} `json:"routes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, 0, 0, err
+	}
+	if len(out.Routes) == 0 || len(out.Routes[0].Sections) == 0 {
+		return 0, 0, 0, fmt.Errorf("here: no route found")
+	}
+	summary := out.Routes[0].Sections[0].Summary
+	withTraffic = time.Duration(summary.Duration) * time.Second
+	noTraffic = withTraffic
+	if summary.BaseDuration > 0 {
+		noTraffic = time.Duration(summary.BaseDuration) * time.Second
+	}
+	return withTraffic, noTraffic, float64(summary.Length), nil
+}
+
+// orsProvider queries the OpenRouteService directions API.
+type orsProvider struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+var orsAPIKeyEnv = "TRAVEL_ORS_API_KEY"
+
+func newORSProvider() (*orsProvider, error) {
+	apiKey := os.Getenv(orsAPIKeyEnv)
+	if apiKey == "" {
+		return nil, fmt.Errorf("missing %s", orsAPIKeyEnv)
+	}
+	return &orsProvider{baseURL: "https://api.openrouteservice.org", apiKey: apiKey, http: http.DefaultClient}, nil
+}
+
+func (p *orsProvider) Name() string { return "openrouteservice" }
+
+// orsProfiles maps a maps.Mode to an OpenRouteService directions
+// profile. OpenRouteService has no transit profile.
+var orsProfiles = map[string]string{
+	string(maps.TravelModeDriving):   "driving-car",
+	string(maps.TravelModeWalking):   "foot-walking",
+	string(maps.TravelModeBicycling): "cycling-regular",
+}
+
+func (p *orsProvider) TravelTime(ctx context.Context, origin, destination LatLngName, depart time.Time, mode string) (withTraffic, noTraffic time.Duration, distanceMeters float64, err error) {
+	profile, ok := orsProfiles[mode]
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("openrouteservice: unsupported mode %q", mode)
+	}
+	url := fmt.Sprintf("%s/v2/directions/%s?api_key=%s&start=%f,%f&end=%f,%f",
+		p.baseURL, profile, p.apiKey, origin.Lng, origin.Lat, destination.Lng, destination.Lat)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return 0, 0, 0, fmt.Errorf("openrouteservice: quota exhausted (429)")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, 0, fmt.Errorf("openrouteservice: unexpected status %d", resp.StatusCode)
+	}
+	var out struct {
+		Features []struct {
+			Properties struct {
+				Summary struct {
+					Duration float64 `json:"duration"`
+					Distance float64 `json:"distance"`
+				} `json:"summary"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, 0, 0, err
+	}
+	if len(out.Features) == 0 {
+		return 0, 0, 0, fmt.Errorf("openrouteservice: no route found")
+	}
+	summary := out.Features[0].Properties.Summary
+	d := time.Duration(summary.Duration) * time.Second
+	return d, d, summary.Distance, nil
+}
+
+// quotaTracker remembers which providers have reported a quota error,
+// persisted to disk so the fallback chain keeps skipping them across
+// restarts until quotaBackoff has elapsed.
+//
+// isQuotaError can't tell a genuinely exhausted daily quota (Google's
+// OVER_QUERY_LIMIT) apart from a transient per-minute rate limit (a bare
+// "429"), so a single noisy 429 from something like OSRM's public demo
+// server would otherwise take that provider out of the chain for as long
+// as the backoff runs. quotaBackoff is deliberately short by default;
+// TRAVEL_QUOTA_BACKOFF can widen it for providers whose quota really does
+// reset once a day.
+type quotaTracker struct {
+	mu   sync.Mutex
+	path string
+	data map[string]time.Time // provider name -> time until which it is skipped
+}
+
+// quotaFileEnv overrides where the quota state file is stored.
+var quotaFileEnv = "TRAVEL_QUOTA_FILE"
+
+// quotaBackoffEnv overrides how long a provider is skipped for after
+// reporting a quota error, e.g. "15m".
+var quotaBackoffEnv = "TRAVEL_QUOTA_BACKOFF"
+
+// defaultQuotaBackoff applies when quotaBackoffEnv is unset or invalid.
+var defaultQuotaBackoff = 15 * time.Minute
+
+func quotaBackoff() time.Duration {
+	if raw := os.Getenv(quotaBackoffEnv); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			return parsed
+		}
+	}
+	return defaultQuotaBackoff
+}
+
+func quotaFilePath() string {
+	if path := os.Getenv(quotaFileEnv); path != "" {
+		return path
+	}
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".cache")
+		}
+	}
+	return filepath.Join(dir, "traveltime", "quota.json")
+}
+
+func loadQuotaTracker() *quotaTracker {
+	t := &quotaTracker{path: quotaFilePath(), data: map[string]time.Time{}}
+	if b, err := os.ReadFile(t.path); err == nil {
+		_ = json.Unmarshal(b, &t.data)
+	}
+	return t
+}
+
+func (t *quotaTracker) exhausted(provider string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Now().Before(t.data[provider])
+}
+
+func (t *quotaTracker) markExhausted(provider string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.data[provider] = time.Now().Add(quotaBackoff())
+	t.save()
+}
+
+func (t *quotaTracker) save() {
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o755); err != nil {
+		return
+	}
+	b, err := json.Marshal(t.data)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(t.path, b, 0o644)
+}
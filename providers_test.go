@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+func TestOSRMProviderParsesResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"routes":[{"duration":930,"distance":12500}]}`)
+	}))
+	defer server.Close()
+
+	p := &osrmProvider{baseURL: server.URL, http: server.Client()}
+	origin := LatLngName{LatLng: maps.LatLng{Lat: 52.5, Lng: 13.4}}
+	destination := LatLngName{LatLng: maps.LatLng{Lat: 52.4, Lng: 13.1}}
+
+	withTraffic, noTraffic, distanceMeters, err := p.TravelTime(context.Background(), origin, destination, time.Now(), string(maps.TravelModeDriving))
+	if err != nil {
+		t.Fatalf("TravelTime() error = %v", err)
+	}
+	if withTraffic != 930*time.Second || noTraffic != 930*time.Second {
+		t.Fatalf("TravelTime() durations = %s/%s, want 930s/930s", withTraffic, noTraffic)
+	}
+	if distanceMeters != 12500 {
+		t.Fatalf("TravelTime() distanceMeters = %v, want 12500", distanceMeters)
+	}
+}
+
+func TestOSRMProviderUnsupportedMode(t *testing.T) {
+	p := newOSRMProvider()
+	_, _, _, err := p.TravelTime(context.Background(), LatLngName{}, LatLngName{}, time.Now(), string(maps.TravelModeTransit))
+	if err == nil {
+		t.Fatal("TravelTime() with transit mode: want error, got nil")
+	}
+}
+
+func TestMapboxProviderParsesResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"routes":[{"duration":1200,"duration_typical":900,"distance":15000}]}`)
+	}))
+	defer server.Close()
+
+	p := &mapboxProvider{token: "test-token", http: server.Client()}
+	p.baseURL = server.URL
+	origin := LatLngName{LatLng: maps.LatLng{Lat: 52.5, Lng: 13.4}}
+	destination := LatLngName{LatLng: maps.LatLng{Lat: 52.4, Lng: 13.1}}
+
+	withTraffic, noTraffic, distanceMeters, err := p.TravelTime(context.Background(), origin, destination, time.Now(), string(maps.TravelModeDriving))
+	if err != nil {
+		t.Fatalf("TravelTime() error = %v", err)
+	}
+	if withTraffic != 1200*time.Second {
+		t.Fatalf("TravelTime() withTraffic = %s, want 1200s (duration)", withTraffic)
+	}
+	if noTraffic != 900*time.Second {
+		t.Fatalf("TravelTime() noTraffic = %s, want 900s (duration_typical)", noTraffic)
+	}
+	if distanceMeters != 15000 {
+		t.Fatalf("TravelTime() distanceMeters = %v, want 15000", distanceMeters)
+	}
+}
+
+func TestHEREProviderParsesResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"routes":[{"sections":[{"summary":{"duration":1500,"baseDuration":1100,"length":18000}}]}]}`)
+	}))
+	defer server.Close()
+
+	p := &hereProvider{apiKey: "test-key", http: server.Client()}
+	p.baseURL = server.URL
+	origin := LatLngName{LatLng: maps.LatLng{Lat: 52.5, Lng: 13.4}}
+	destination := LatLngName{LatLng: maps.LatLng{Lat: 52.4, Lng: 13.1}}
+
+	withTraffic, noTraffic, distanceMeters, err := p.TravelTime(context.Background(), origin, destination, time.Now(), string(maps.TravelModeDriving))
+	if err != nil {
+		t.Fatalf("TravelTime() error = %v", err)
+	}
+	if withTraffic != 1500*time.Second {
+		t.Fatalf("TravelTime() withTraffic = %s, want 1500s (duration)", withTraffic)
+	}
+	if noTraffic != 1100*time.Second {
+		t.Fatalf("TravelTime() noTraffic = %s, want 1100s (baseDuration)", noTraffic)
+	}
+	if distanceMeters != 18000 {
+		t.Fatalf("TravelTime() distanceMeters = %v, want 18000", distanceMeters)
+	}
+}
+
+func TestORSProviderParsesResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"features":[{"properties":{"summary":{"duration":800,"distance":9000}}}]}`)
+	}))
+	defer server.Close()
+
+	p := &orsProvider{apiKey: "test-key", http: server.Client()}
+	p.baseURL = server.URL
+	origin := LatLngName{LatLng: maps.LatLng{Lat: 52.5, Lng: 13.4}}
+	destination := LatLngName{LatLng: maps.LatLng{Lat: 52.4, Lng: 13.1}}
+
+	withTraffic, noTraffic, distanceMeters, err := p.TravelTime(context.Background(), origin, destination, time.Now(), string(maps.TravelModeDriving))
+	if err != nil {
+		t.Fatalf("TravelTime() error = %v", err)
+	}
+	if withTraffic != 800*time.Second || noTraffic != 800*time.Second {
+		t.Fatalf("TravelTime() durations = %s/%s, want 800s/800s", withTraffic, noTraffic)
+	}
+	if distanceMeters != 9000 {
+		t.Fatalf("TravelTime() distanceMeters = %v, want 9000", distanceMeters)
+	}
+}
+
+// stubProvider is a minimal ETAProvider for exercising providerChain without
+// any real network calls.
+type stubProvider struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (s *stubProvider) Name() string { return s.name }
+
+func (s *stubProvider) TravelTime(ctx context.Context, origin, destination LatLngName, depart time.Time, mode string) (time.Duration, time.Duration, float64, error) {
+	s.calls++
+	if s.err != nil {
+		return 0, 0, 0, s.err
+	}
+	return time.Minute, time.Minute, 1000, nil
+}
+
+// newTestQuotaTracker returns a quotaTracker backed by a scratch file under
+// t's temp dir, so tests never touch the real $XDG_CACHE_HOME state.
+func newTestQuotaTracker(t *testing.T) *quotaTracker {
+	t.Helper()
+	return &quotaTracker{path: t.TempDir() + "/quota.json", data: map[string]time.Time{}}
+}
+
+func TestProviderChainFallsBackOnError(t *testing.T) {
+	chain := newProviderChain([]ETAProvider{
+		&stubProvider{name: "broken", err: errors.New("connection refused")},
+		&stubProvider{name: "good"},
+	}, newTestQuotaTracker(t))
+
+	withTraffic, _, _, err := chain.TravelTime(context.Background(), LatLngName{}, LatLngName{}, time.Now(), "driving")
+	if err != nil {
+		t.Fatalf("TravelTime() error = %v", err)
+	}
+	if withTraffic != time.Minute {
+		t.Fatalf("TravelTime() = %s, want the fallback provider's 1m", withTraffic)
+	}
+}
+
+func TestProviderChainSkipsExhaustedProvider(t *testing.T) {
+	quota := newTestQuotaTracker(t)
+	quota.markExhausted("over-quota")
+
+	attempted := &stubProvider{name: "over-quota"}
+	chain := newProviderChain([]ETAProvider{attempted, &stubProvider{name: "good"}}, quota)
+
+	withTraffic, _, _, err := chain.TravelTime(context.Background(), LatLngName{}, LatLngName{}, time.Now(), "driving")
+	if err != nil {
+		t.Fatalf("TravelTime() error = %v", err)
+	}
+	if withTraffic != time.Minute {
+		t.Fatalf("TravelTime() = %s, want the non-exhausted provider's 1m", withTraffic)
+	}
+	if attempted.calls != 0 {
+		t.Fatalf("exhausted provider was called %d times, want 0", attempted.calls)
+	}
+}
+
+func TestProviderChainMarksQuotaErrorExhausted(t *testing.T) {
+	quota := newTestQuotaTracker(t)
+	chain := newProviderChain([]ETAProvider{&stubProvider{name: "google", err: errors.New("OVER_QUERY_LIMIT")}}, quota)
+
+	if _, _, _, err := chain.TravelTime(context.Background(), LatLngName{}, LatLngName{}, time.Now(), "driving"); err == nil {
+		t.Fatal("TravelTime() with no working providers: want error, got nil")
+	}
+	if !quota.exhausted("google") {
+		t.Fatal("quota.exhausted(\"google\") = false, want true after an OVER_QUERY_LIMIT error")
+	}
+}
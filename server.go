@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+// serveAddrEnv configures the daemon's listen address, e.g. ":8080". When
+// unset, traveltime runs in its original one-shot mode.
+var serveAddrEnv = "TRAVEL_SERVE_ADDR"
+
+// servePollIntervalEnv configures how often the daemon refreshes its travel
+// time from the configured providers.
+var servePollIntervalEnv = "TRAVEL_SERVE_INTERVAL"
+
+// defaultServePollInterval applies when servePollIntervalEnv is unset or invalid.
+var defaultServePollInterval = 5 * time.Minute
+
+// travelServer polls an ETAProvider on an interval and serves the latest
+// TravelResult as JSON and as Prometheus gauges, so statusbar tools and
+// Grafana can read a fast local endpoint instead of shelling out and hitting
+// the upstream provider on every refresh.
+type travelServer struct {
+	client     *maps.Client
+	provider   ETAProvider
+	work, home []LatLngName
+	modes      []string
+	interval   time.Duration
+
+	mu     sync.RWMutex
+	latest *TravelResult
+}
+
+func newTravelServer(client *maps.Client, provider ETAProvider, work, home []LatLngName, modes []string, interval time.Duration) *travelServer {
+	return &travelServer{client: client, provider: provider, work: work, home: home, modes: modes, interval: interval}
+}
+
+// run polls immediately and then on every tick of s.interval, until ctx is done.
+func (s *travelServer) run(ctx context.Context) {
+	s.poll(ctx)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.poll(ctx)
+		}
+	}
+}
+
+func (s *travelServer) poll(ctx context.Context) {
+	pollCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := fetchTravelResult(pollCtx, s.client, s.provider, s.work, s.home, s.modes)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	s.mu.Lock()
+	s.latest = result
+	s.mu.Unlock()
+}
+
+func (s *travelServer) handleTravelTime(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	result := s.latest
+	s.mu.RUnlock()
+	if result == nil {
+		http.Error(w, "no travel time polled yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Println("failed to encode travel time response:", err)
+	}
+}
+
+func (s *travelServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	result := s.latest
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if result == nil {
+		return
+	}
+	labels := fmt.Sprintf(`origin=%q,destination=%q`, result.Origin.Name, result.Destination.Name)
+	fmt.Fprintf(w, "# HELP traveltime_with_traffic_minutes Travel time including traffic delay, in minutes.\n")
+	fmt.Fprintf(w, "# TYPE traveltime_with_traffic_minutes gauge\n")
+	fmt.Fprintf(w, "traveltime_with_traffic_minutes{%s} %d\n", labels, result.WithTraffic)
+	fmt.Fprintf(w, "# HELP traveltime_no_traffic_minutes Travel time without traffic delay, in minutes.\n")
+	fmt.Fprintf(w, "# TYPE traveltime_no_traffic_minutes gauge\n")
+	fmt.Fprintf(w, "traveltime_no_traffic_minutes{%s} %d\n", labels, result.NoTraffic)
+	fmt.Fprintf(w, "# HELP traveltime_deviation_percent Relative traffic delay, in percent.\n")
+	fmt.Fprintf(w, "# TYPE traveltime_deviation_percent gauge\n")
+	fmt.Fprintf(w, "traveltime_deviation_percent{%s} %s\n", labels, result.Deviation.Relative)
+}
+
+// serve runs traveltime as a long-lived daemon: it polls client/provider for
+// work/home in the background and exposes /traveltime and /metrics on addr.
+// It blocks until the server stops, and calls log.Fatal on listen failure.
+func serve(addr string, client *maps.Client, provider ETAProvider, work, home []LatLngName, modes []string) {
+	interval := defaultServePollInterval
+	if raw := os.Getenv(servePollIntervalEnv); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			interval = parsed
+		}
+	}
+	server := newTravelServer(client, provider, work, home, modes, interval)
+	go server.run(context.Background())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/traveltime", server.handleTravelTime)
+	mux.HandleFunc("/metrics", server.handleMetrics)
+
+	log.Printf("serving traveltime on %s (polling every %s)", addr, interval)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
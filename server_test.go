@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleTravelTimeBeforeFirstPoll(t *testing.T) {
+	s := newTravelServer(nil, nil, nil, nil, nil, 0)
+
+	rec := httptest.NewRecorder()
+	s.handleTravelTime(rec, httptest.NewRequest(http.MethodGet, "/traveltime", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("handleTravelTime() status = %d, want 503 before the first poll completes", rec.Code)
+	}
+}
+
+func TestHandleTravelTimeAndMetricsAfterPoll(t *testing.T) {
+	s := newTravelServer(nil, nil, nil, nil, nil, 0)
+	s.latest = &TravelResult{
+		Origin:      LatLngName{Name: "home"},
+		Destination: LatLngName{Name: "work"},
+		WithTraffic: 30,
+		NoTraffic:   20,
+		Deviation:   Deviation{Relative: "+50%", Absolute: "+10"},
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleTravelTime(rec, httptest.NewRequest(http.MethodGet, "/traveltime", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("handleTravelTime() status = %d, want 200", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"WithTraffic":30`) {
+		t.Fatalf("handleTravelTime() body = %q, want it to contain WithTraffic=30", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, `traveltime_with_traffic_minutes{origin="home",destination="work"} 30`) {
+		t.Fatalf("handleMetrics() body = %q, missing expected with_traffic gauge", body)
+	}
+	if !strings.Contains(body, `traveltime_no_traffic_minutes{origin="home",destination="work"} 20`) {
+		t.Fatalf("handleMetrics() body = %q, missing expected no_traffic gauge", body)
+	}
+}
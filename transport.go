@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// httpMaxRetriesEnv and httpInitialWaitEnv configure the retrying transport
+// wrapped around the Google Maps client's HTTP requests.
+var (
+	httpMaxRetriesEnv  = "TRAVEL_HTTP_MAX_RETRIES"
+	httpInitialWaitEnv = "TRAVEL_HTTP_INITIAL_WAIT"
+)
+
+// defaultHTTPMaxRetries and defaultHTTPInitialWait apply when the
+// corresponding env vars are unset or invalid.
+var (
+	defaultHTTPMaxRetries  = 5
+	defaultHTTPInitialWait = time.Second
+)
+
+// retryJitter is the maximum +/- jitter applied to each backoff wait.
+var retryJitter = 500 * time.Millisecond
+
+// retryRoundTripper retries requests that fail with a network error or a 5xx
+// response, using exponential backoff with jitter between attempts, so a
+// transient Google Maps API failure doesn't turn into a hard log.Fatal.
+type retryRoundTripper struct {
+	next        http.RoundTripper
+	maxRetries  int
+	initialWait time.Duration
+}
+
+func newRetryTransport() *retryRoundTripper {
+	maxRetries := defaultHTTPMaxRetries
+	if raw := os.Getenv(httpMaxRetriesEnv); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil {
+			maxRetries = parsed
+		}
+	}
+	initialWait := defaultHTTPInitialWait
+	if raw := os.Getenv(httpInitialWaitEnv); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil {
+			initialWait = parsed
+		}
+	}
+	return &retryRoundTripper{next: http.DefaultTransport, maxRetries: maxRetries, initialWait: initialWait}
+}
+
+func (t *retryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	wait := t.initialWait
+	var resp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if req.GetBody != nil {
+				body, bodyErr := req.GetBody()
+				if bodyErr != nil {
+					return resp, bodyErr
+				}
+				req.Body = body
+			}
+			jitter := time.Duration(rand.Int63n(int64(2*retryJitter))) - retryJitter
+			timer := time.NewTimer(wait + jitter)
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
+			wait *= 2
+		}
+		resp, err = t.next.RoundTrip(req)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			return resp, nil
+		}
+		if attempt >= t.maxRetries {
+			return resp, err
+		}
+		if err == nil {
+			resp.Body.Close()
+		}
+	}
+}
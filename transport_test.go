@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryRoundTripperRetriesOn5xxThenSucceeds(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := &retryRoundTripper{next: http.DefaultTransport, maxRetries: 5, initialWait: time.Millisecond}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Get() status = %d, want 200", resp.StatusCode)
+	}
+	if attempts != 3 {
+		t.Fatalf("server saw %d attempts, want 3", attempts)
+	}
+}
+
+func TestRetryRoundTripperGivesUpAfterMaxRetries(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	transport := &retryRoundTripper{next: http.DefaultTransport, maxRetries: 2, initialWait: time.Millisecond}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Get() status = %d, want 503", resp.StatusCode)
+	}
+	if attempts != 3 {
+		t.Fatalf("server saw %d attempts, want 3 (1 initial + 2 retries)", attempts)
+	}
+}